@@ -0,0 +1,110 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/storage"
+)
+
+var cmdFixSuperblock = &Command{
+	UsageLine: "fix-superblock -dir=/tmp -volumeId=234",
+	Short:     "check and fix inconsistencies of volume's super block",
+	Long: `Decode a volume's super block, verifying its checksum, and print every
+decoded TLV extension frame (erasure-coding descriptor, tiered-storage
+pointer, etc), skipping and reporting any frame type it does not recognize.
+
+With -force, the super block is rewritten from the decoded fields, which
+repairs an incorrect Version4 checksum. A legacy super block keeps its
+legacy layout, which has no checksum, unless -upgrade is also given.
+
+With -force -upgrade, a legacy Version1/Version2/Version3 super block is
+also migrated to the checksummed Version4 format.
+
+  `,
+}
+
+var (
+	fixSuperblockVolumePath = cmdFixSuperblock.Flag.String("dir", ".", "data directory to store files")
+	fixSuperblockVolumeId   = cmdFixSuperblock.Flag.Int("volumeId", -1, "a volume id. The volume should already exist in the dir.")
+	fixSuperblockForce      = cmdFixSuperblock.Flag.Bool("force", false, "force write the fixed super block")
+	fixSuperblockUpgrade    = cmdFixSuperblock.Flag.Bool("upgrade", false, "with -force, also migrate a legacy super block to the checksummed Version4 format")
+)
+
+func init() {
+	cmdFixSuperblock.Run = runFixSuperblock
+	Commands = append(Commands, cmdFixSuperblock)
+}
+
+func runFixSuperblock(cmd *Command, args []string) bool {
+
+	if *fixSuperblockVolumeId == -1 {
+		fmt.Println("needs to specify the volume id to fix")
+		return false
+	}
+
+	fileName := strconv.Itoa(*fixSuperblockVolumeId)
+	dataFile, err := os.OpenFile(*fixSuperblockVolumePath+"/"+fileName+".dat", os.O_RDWR, 0644)
+	if err != nil {
+		glog.Errorf("cannot open dat file: %v", err)
+		return false
+	}
+	defer dataFile.Close()
+
+	superBlock, err := storage.ReadSuperBlock(dataFile)
+	if err != nil {
+		fmt.Printf("existing super block is invalid: %v\n", err)
+		if !*fixSuperblockForce {
+			fmt.Println("re-run with -force to rewrite a fresh super block using the decoded fields")
+			return false
+		}
+	} else {
+		fmt.Printf("version: %v, replication: %v, ttl: %v, compact revision: %v\n",
+			superBlock.Version(), superBlock.ReplicaPlacement, superBlock.Ttl, superBlock.CompactRevision)
+		if descriptor := superBlock.EncryptionDescriptor(); descriptor != nil {
+			fmt.Printf("encryption: cipher=%d key_id=%s\n", descriptor.CipherId, descriptor.KeyId)
+		}
+		for _, frame := range superBlock.Frames {
+			switch frame.Type {
+			case storage.FrameTypeErasureCoding:
+				fmt.Printf("frame: erasure-coding descriptor (%d bytes)\n", len(frame.Data))
+			case storage.FrameTypeTieredStorage:
+				fmt.Printf("frame: tiered-storage descriptor (%d bytes)\n", len(frame.Data))
+			default:
+				fmt.Printf("frame: unknown type %d, skipping (%d bytes)\n", frame.Type, len(frame.Data))
+			}
+		}
+	}
+
+	if !*fixSuperblockForce {
+		return true
+	}
+
+	// When the header cannot be read or its replica-placement byte cannot be
+	// decoded, ReadSuperBlock returns an error and leaves ReplicaPlacement or
+	// Ttl nil; with -force we still get here, and Bytes() would panic
+	// dereferencing them. Refuse to blindly rewrite in that case instead of
+	// crashing the recovery tool on exactly the corrupted input it's meant to fix.
+	if superBlock.ReplicaPlacement == nil || superBlock.Ttl == nil {
+		fmt.Println("cannot rewrite: replica placement or TTL could not be decoded, super block is too corrupted to repair automatically")
+		return false
+	}
+
+	if *fixSuperblockUpgrade {
+		superBlock = storage.UpgradeSuperBlock(superBlock)
+	}
+
+	if _, err := dataFile.WriteAt(superBlock.Bytes(), 0); err != nil {
+		glog.Errorf("cannot rewrite super block: %v", err)
+		return false
+	}
+	if superBlock.Version() == storage.Version4 {
+		fmt.Println("super block checksum repaired")
+	} else {
+		fmt.Println("super block rewritten in the legacy layout, without a checksum")
+	}
+
+	return true
+}
@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// AzureBackend is a TieredBackend backed by Azure Blob Storage.
+type AzureBackend struct {
+	ContainerURL azblob.ContainerURL
+}
+
+func (b *AzureBackend) ReadRange(bucket, key string, offset, size int64) (io.ReadCloser, error) {
+	blobURL := b.ContainerURL.NewBlobURL(key)
+	resp, err := blobURL.Download(context.Background(), offset, size, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		return nil, fmt.Errorf("azure blob download %s/%s: %v", bucket, key, err)
+	}
+	return resp.Body(azblob.RetryReaderOptions{}), nil
+}
@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"cloud.google.com/go/storage"
+)
+
+// GcsBackend is a TieredBackend backed by Google Cloud Storage.
+type GcsBackend struct {
+	Client *storage.Client
+}
+
+func (b *GcsBackend) ReadRange(bucket, key string, offset, size int64) (io.ReadCloser, error) {
+	reader, err := b.Client.Bucket(bucket).Object(key).NewRangeReader(context.Background(), offset, size)
+	if err != nil {
+		return nil, fmt.Errorf("gcs NewRangeReader %s/%s: %v", bucket, key, err)
+	}
+	return reader, nil
+}
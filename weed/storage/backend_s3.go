@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// S3Backend is a TieredBackend backed by AWS S3 (or an S3-compatible store).
+type S3Backend struct {
+	Client *s3.S3
+}
+
+func (b *S3Backend) ReadRange(bucket, key string, offset, size int64) (io.ReadCloser, error) {
+	rangeHeader := fmt.Sprintf("bytes=%d-%d", offset, offset+size-1)
+	resp, err := b.Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(rangeHeader),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 GetObject %s/%s: %v", bucket, key, err)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"os"
+)
+
+// VolumeId identifies a volume within a topology. It is a small positive
+// integer assigned by the master when the volume is first allocated.
+type VolumeId uint32
+
+// Volume represents one .dat/.idx pair on a volume server.
+type Volume struct {
+	Id        VolumeId
+	dataFile  *os.File
+	readOnly  bool
+	SuperBlock SuperBlock
+
+	// KeyProvider resolves the KEK used to wrap/unwrap this volume's DEK.
+	// Nil means the volume is not encrypted.
+	KeyProvider KeyProvider
+	// EncryptionKeyId is the key id passed to KeyProvider when a new DEK
+	// needs to be wrapped, e.g. on first write of a new volume.
+	EncryptionKeyId string
+
+	// remoteCache caches needle ranges fetched from a tiered backend for a
+	// remote volume (see IsRemote). Nil for a volume that still holds its
+	// needle bodies locally.
+	remoteCache *RemoteNeedleCache
+}
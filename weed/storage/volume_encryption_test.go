@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+)
+
+type fixedKeyProvider struct {
+	key []byte
+}
+
+func (p *fixedKeyProvider) GetKey(keyId string) ([]byte, error) {
+	return p.key, nil
+}
+
+func TestEncryptDecryptNeedleRoundTrip(t *testing.T) {
+	provider := &fixedKeyProvider{key: bytes.Repeat([]byte{0x42}, dekSize)}
+
+	sb := &SuperBlock{}
+	if err := sb.setupEncryption(provider, "key-1"); err != nil {
+		t.Fatalf("setupEncryption: %v", err)
+	}
+
+	plaintext := []byte("hello needle")
+	ciphertext, err := sb.EncryptNeedle(provider, 123, 4096, plaintext)
+	if err != nil {
+		t.Fatalf("EncryptNeedle: %v", err)
+	}
+	if bytes.Equal(ciphertext, plaintext) {
+		t.Fatalf("ciphertext should not equal plaintext")
+	}
+
+	decrypted, err := sb.DecryptNeedle(provider, 123, 4096, ciphertext)
+	if err != nil {
+		t.Fatalf("DecryptNeedle: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("decrypted = %q, want %q", decrypted, plaintext)
+	}
+}
+
+func TestDecryptNeedleFailsWithWrongNeedleId(t *testing.T) {
+	provider := &fixedKeyProvider{key: bytes.Repeat([]byte{0x07}, dekSize)}
+
+	sb := &SuperBlock{}
+	if err := sb.setupEncryption(provider, "key-1"); err != nil {
+		t.Fatalf("setupEncryption: %v", err)
+	}
+
+	ciphertext, err := sb.EncryptNeedle(provider, 1, 0, []byte("payload"))
+	if err != nil {
+		t.Fatalf("EncryptNeedle: %v", err)
+	}
+
+	// The needle id is bound in as AEAD additional data, so decrypting as if
+	// it belonged to a different needle at the same offset must fail rather
+	// than silently returning wrong plaintext.
+	if _, err := sb.DecryptNeedle(provider, 2, 0, ciphertext); err == nil {
+		t.Fatalf("expected DecryptNeedle to fail for mismatched needle id")
+	}
+}
+
+func TestNeedleNonceDoesNotCollideAcrossOffsets(t *testing.T) {
+	prefix := []byte{1, 2, 3, 4}
+	a := needleNonce(prefix, 5)
+	b := needleNonce(prefix, 6)
+	if bytes.Equal(a, b) {
+		t.Fatalf("nonces for distinct offsets must differ")
+	}
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/util"
+	"github.com/klauspost/reedsolomon"
+)
+
+func newReedSolomon(dataShards, parityShards int) (reedsolomon.Encoder, error) {
+	return reedsolomon.New(dataShards, parityShards)
+}
+
+// ErasureCodingDescriptor describes how a sealed, read-only volume has been
+// split into data and parity shards, so a missing shard can be rebuilt from
+// the surviving ones without needing the original .dat file. It is carried
+// as a TLV extension frame (FrameTypeErasureCoding) rather than a protobuf
+// field so older binaries can skip it gracefully.
+type ErasureCodingDescriptor struct {
+	DataShards   int      // e.g. 10 for Reed-Solomon 10+4
+	ParityShards int      // e.g. 4 for Reed-Solomon 10+4
+	ShardIndex   int      // which shard this volume replica holds, 0-based
+	StripeSize   int64    // number of bytes per stripe across data shards
+	Generation   uint32   // bumped every time the volume is re-encoded
+	Peers        []string // volume servers holding the other shards, indexed like ShardIndex
+}
+
+func (d *ErasureCodingDescriptor) TotalShards() int {
+	return d.DataShards + d.ParityShards
+}
+
+// marshalErasureCodingDescriptor encodes the descriptor into the flat
+// length-prefixed form stored in the extension frame.
+func marshalErasureCodingDescriptor(d *ErasureCodingDescriptor) []byte {
+	data := make([]byte, 4+4+4+8+4)
+	util.Uint32toBytes(data[0:4], uint32(d.DataShards))
+	util.Uint32toBytes(data[4:8], uint32(d.ParityShards))
+	util.Uint32toBytes(data[8:12], uint32(d.ShardIndex))
+	util.Uint64toBytes(data[12:20], uint64(d.StripeSize))
+	util.Uint32toBytes(data[20:24], d.Generation)
+	for _, peer := range d.Peers {
+		peerBytes := []byte(peer)
+		lenBytes := make([]byte, 2)
+		util.Uint16toBytes(lenBytes, uint16(len(peerBytes)))
+		data = append(data, lenBytes...)
+		data = append(data, peerBytes...)
+	}
+	return data
+}
+
+func unmarshalErasureCodingDescriptor(data []byte) (*ErasureCodingDescriptor, error) {
+	if len(data) < 24 {
+		return nil, fmt.Errorf("erasure coding descriptor too short: %d bytes", len(data))
+	}
+	d := &ErasureCodingDescriptor{
+		DataShards:   int(util.BytesToUint32(data[0:4])),
+		ParityShards: int(util.BytesToUint32(data[4:8])),
+		ShardIndex:   int(util.BytesToUint32(data[8:12])),
+		StripeSize:   int64(util.BytesToUint64(data[12:20])),
+		Generation:   util.BytesToUint32(data[20:24]),
+	}
+	offset := 24
+	for offset < len(data) {
+		if offset+2 > len(data) {
+			return nil, fmt.Errorf("truncated peer entry at offset %d", offset)
+		}
+		peerLen := int(util.BytesToUint16(data[offset : offset+2]))
+		offset += 2
+		if offset+peerLen > len(data) {
+			return nil, fmt.Errorf("truncated peer entry at offset %d", offset)
+		}
+		d.Peers = append(d.Peers, string(data[offset:offset+peerLen]))
+		offset += peerLen
+	}
+	return d, nil
+}
+
+// ErasureCodingDescriptor returns the volume's EC descriptor, or nil if the
+// volume has not been erasure-coded.
+func (s *SuperBlock) ErasureCodingDescriptor() (*ErasureCodingDescriptor, error) {
+	frame := s.FrameByType(FrameTypeErasureCoding)
+	if frame == nil {
+		return nil, nil
+	}
+	return unmarshalErasureCodingDescriptor(frame.Data)
+}
+
+// SetErasureCodingDescriptor seals the volume under the given EC descriptor,
+// replacing any previous one.
+func (s *SuperBlock) SetErasureCodingDescriptor(d *ErasureCodingDescriptor) {
+	newFrame := ExtensionFrame{Type: FrameTypeErasureCoding, Data: marshalErasureCodingDescriptor(d)}
+	for i := range s.Frames {
+		if s.Frames[i].Type == FrameTypeErasureCoding {
+			s.Frames[i] = newFrame
+			return
+		}
+	}
+	s.Frames = append(s.Frames, newFrame)
+}
+
+// ShardProvider fetches the raw bytes of a surviving EC shard from whichever
+// volume server holds it, so ReconstructShard does not need to know about
+// the cluster's RPC layer.
+type ShardProvider interface {
+	FetchShard(peer string, shardIndex int) (data []byte, err error)
+}
+
+// ReconstructShard rebuilds the given missing shard using the surviving
+// shards listed in the volume's EC descriptor. It requires at least
+// DataShards of the TotalShards() shards to be available.
+func (v *Volume) ReconstructShard(shardIdx int, provider ShardProvider) ([]byte, error) {
+	descriptor, err := v.SuperBlock.ErasureCodingDescriptor()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read erasure coding descriptor: %v", err)
+	}
+	if descriptor == nil {
+		return nil, fmt.Errorf("volume %d is not erasure-coded", v.Id)
+	}
+	if shardIdx < 0 || shardIdx >= descriptor.TotalShards() {
+		return nil, fmt.Errorf("shard index %d out of range [0,%d)", shardIdx, descriptor.TotalShards())
+	}
+
+	shards := make([][]byte, descriptor.TotalShards())
+	available := 0
+	for i, peer := range descriptor.Peers {
+		if i == shardIdx || peer == "" {
+			continue
+		}
+		data, err := provider.FetchShard(peer, i)
+		if err != nil {
+			glog.V(0).Infof("failed to fetch shard %d from %s: %v", i, peer, err)
+			continue
+		}
+		shards[i] = data
+		available++
+		if available >= descriptor.DataShards {
+			break
+		}
+	}
+	if available < descriptor.DataShards {
+		return nil, fmt.Errorf("only %d of the required %d shards are available to reconstruct shard %d",
+			available, descriptor.DataShards, shardIdx)
+	}
+
+	return reedSolomonReconstruct(shards, descriptor.DataShards, descriptor.ParityShards, shardIdx)
+}
+
+// reedSolomonReconstruct rebuilds the shard at shardIdx from the given set of
+// data/parity shards using Reed-Solomon erasure coding.
+func reedSolomonReconstruct(shards [][]byte, dataShards, parityShards, shardIdx int) ([]byte, error) {
+	enc, err := newReedSolomon(dataShards, parityShards)
+	if err != nil {
+		return nil, fmt.Errorf("cannot initialize reed-solomon encoder: %v", err)
+	}
+	if err := enc.Reconstruct(shards); err != nil {
+		return nil, fmt.Errorf("cannot reconstruct shard %d: %v", shardIdx, err)
+	}
+	return shards[shardIdx], nil
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+// stubShardProvider serves shards out of an in-memory map, standing in for
+// fetching them from peer volume servers over RPC.
+type stubShardProvider struct {
+	shards map[int][]byte
+}
+
+func (p *stubShardProvider) FetchShard(peer string, shardIndex int) ([]byte, error) {
+	data, ok := p.shards[shardIndex]
+	if !ok {
+		return nil, fmt.Errorf("no shard %d available from %s", shardIndex, peer)
+	}
+	return data, nil
+}
+
+func TestReconstructShard(t *testing.T) {
+	const dataShards, parityShards = 2, 1
+	enc, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		t.Fatalf("reedsolomon.New: %v", err)
+	}
+
+	shards := [][]byte{
+		[]byte("AAAAAAAA"),
+		[]byte("BBBBBBBB"),
+		make([]byte, 8),
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	missingIdx := 1
+	lostShard := append([]byte{}, shards[missingIdx]...)
+
+	v := &Volume{Id: 1}
+	v.SuperBlock.SetErasureCodingDescriptor(&ErasureCodingDescriptor{
+		DataShards:   dataShards,
+		ParityShards: parityShards,
+		ShardIndex:   missingIdx,
+		Peers:        []string{"host0", "host1", "host2"},
+	})
+
+	provider := &stubShardProvider{shards: map[int][]byte{
+		0: shards[0],
+		2: shards[2],
+	}}
+
+	rebuilt, err := v.ReconstructShard(missingIdx, provider)
+	if err != nil {
+		t.Fatalf("ReconstructShard: %v", err)
+	}
+	if !bytes.Equal(rebuilt, lostShard) {
+		t.Fatalf("rebuilt shard = %q, want %q", rebuilt, lostShard)
+	}
+}
+
+func TestReconstructShardFailsWithoutEnoughShards(t *testing.T) {
+	v := &Volume{Id: 1}
+	v.SuperBlock.SetErasureCodingDescriptor(&ErasureCodingDescriptor{
+		DataShards:   10,
+		ParityShards: 4,
+		ShardIndex:   0,
+		Peers:        append([]string{"", "host1"}, make([]string, 12)...),
+	})
+
+	provider := &stubShardProvider{shards: map[int][]byte{1: []byte("only-one-shard")}}
+
+	if _, err := v.ReconstructShard(0, provider); err == nil {
+		t.Fatalf("expected ReconstructShard to fail with only one surviving shard")
+	}
+}
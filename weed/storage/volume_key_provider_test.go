@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileKeyProviderRejectsPathTraversal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "key-provider-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	secretOutsideDir := filepath.Join(filepath.Dir(dir), "secret-outside")
+	if err := ioutil.WriteFile(secretOutsideDir, []byte("top-secret"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	defer os.Remove(secretOutsideDir)
+
+	provider := NewFileKeyProvider(dir)
+
+	for _, keyId := range []string{"../secret-outside", "..", "sub/../../secret-outside"} {
+		if _, err := provider.GetKey(keyId); err == nil {
+			t.Errorf("GetKey(%q) should have been rejected, got no error", keyId)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "good-key"), []byte("dek-material"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := provider.GetKey("good-key"); err != nil {
+		t.Errorf("GetKey(\"good-key\") should succeed, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const remoteNeedleCacheMaxEntries = 10000
+
+// OpenVolume opens the volume with the given id under dirname, deciding
+// between local and remote mode purely from what ReadSuperBlock decodes:
+// if the super block carries a TieringDescriptor, the local .dat file holds
+// only the super block (its needle bodies were offloaded), so the file
+// handle used to read the header is not kept around for serving needles -
+// reads are instead routed to ReadRemoteNeedle. A local volume keeps the
+// .dat file open for the lifetime of the Volume, as before.
+func OpenVolume(dirname string, id VolumeId, keyProvider KeyProvider, encryptionKeyId string) (*Volume, error) {
+	v := &Volume{Id: id, KeyProvider: keyProvider, EncryptionKeyId: encryptionKeyId}
+
+	datFileName := filepath.Join(dirname, fmt.Sprintf("%d.dat", id))
+	dataFile, err := os.OpenFile(datFileName, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open volume %d data file: %v", id, err)
+	}
+
+	superBlock, err := ReadSuperBlock(dataFile)
+	if err != nil {
+		dataFile.Close()
+		return nil, fmt.Errorf("cannot read volume %d super block: %v", id, err)
+	}
+	v.SuperBlock = superBlock
+
+	descriptor, err := superBlock.TieringDescriptor()
+	if err != nil {
+		dataFile.Close()
+		return nil, fmt.Errorf("cannot read volume %d tiering descriptor: %v", id, err)
+	}
+	if descriptor != nil {
+		dataFile.Close()
+		cacheDir := filepath.Join(dirname, fmt.Sprintf("%d.remotecache", id))
+		if v.remoteCache, err = NewRemoteNeedleCache(cacheDir, remoteNeedleCacheMaxEntries); err != nil {
+			return nil, fmt.Errorf("cannot create remote needle cache for volume %d: %v", id, err)
+		}
+		return v, nil
+	}
+
+	v.dataFile = dataFile
+	return v, nil
+}
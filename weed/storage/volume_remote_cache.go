@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"container/list"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+// RemoteNeedleCache is a bounded, on-disk LRU cache of needle ranges fetched
+// from a volume's tiered backend, so repeated reads of the same needle don't
+// re-issue a remote range-GET every time.
+type RemoteNeedleCache struct {
+	dir         string
+	maxEntries  int
+	mu          sync.Mutex
+	entries     map[string]*list.Element
+	evictionLog *list.List // most-recently-used at the front
+}
+
+func NewRemoteNeedleCache(dir string, maxEntries int) (*RemoteNeedleCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &RemoteNeedleCache{
+		dir:         dir,
+		maxEntries:  maxEntries,
+		entries:     make(map[string]*list.Element),
+		evictionLog: list.New(),
+	}, nil
+}
+
+func (c *RemoteNeedleCache) pathFor(key string) string {
+	return filepath.Join(c.dir, key)
+}
+
+func (c *RemoteNeedleCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	elem, found := c.entries[key]
+	if found {
+		c.evictionLog.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !found {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(c.pathFor(key))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func (c *RemoteNeedleCache) Add(key string, data []byte) {
+	if err := ioutil.WriteFile(c.pathFor(key), data, 0644); err != nil {
+		glog.V(0).Infof("failed to write remote needle cache entry %s: %v", key, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, found := c.entries[key]; found {
+		c.evictionLog.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.evictionLog.PushFront(key)
+	for len(c.entries) > c.maxEntries {
+		oldest := c.evictionLog.Back()
+		if oldest == nil {
+			break
+		}
+		oldestKey := oldest.Value.(string)
+		c.evictionLog.Remove(oldest)
+		delete(c.entries, oldestKey)
+		if err := os.Remove(c.pathFor(oldestKey)); err != nil && !os.IsNotExist(err) {
+			glog.V(0).Infof("failed to evict remote needle cache entry %s: %v", oldestKey, err)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// TieringDescriptor points at the remote object a sealed, read-only volume
+// has been offloaded to, so a local volume server can keep serving reads
+// without holding the full .dat body on disk. It is carried as a TLV
+// extension frame (FrameTypeTieredStorage).
+type TieringDescriptor struct {
+	BackendId   string // e.g. "s3", "gcs", "azure", matches a registered TieredBackend
+	Bucket      string
+	KeyPrefix   string // object key prefix; "/chunk-<n>" is appended per chunk object
+	UploadEpoch int64  // unix seconds when the offload completed
+	ChunkSize   int64  // size of each remote object the volume was split into
+}
+
+func marshalTieringDescriptor(d *TieringDescriptor) []byte {
+	backendIdBytes := []byte(d.BackendId)
+	bucketBytes := []byte(d.Bucket)
+	keyPrefixBytes := []byte(d.KeyPrefix)
+
+	data := make([]byte, 0, 2+len(backendIdBytes)+2+len(bucketBytes)+2+len(keyPrefixBytes)+8+8)
+	data = appendLenPrefixed(data, backendIdBytes)
+	data = appendLenPrefixed(data, bucketBytes)
+	data = appendLenPrefixed(data, keyPrefixBytes)
+
+	epochAndChunk := make([]byte, 16)
+	util.Uint64toBytes(epochAndChunk[0:8], uint64(d.UploadEpoch))
+	util.Uint64toBytes(epochAndChunk[8:16], uint64(d.ChunkSize))
+	data = append(data, epochAndChunk...)
+
+	return data
+}
+
+func appendLenPrefixed(buf []byte, field []byte) []byte {
+	lenBytes := make([]byte, 2)
+	util.Uint16toBytes(lenBytes, uint16(len(field)))
+	buf = append(buf, lenBytes...)
+	return append(buf, field...)
+}
+
+func unmarshalTieringDescriptor(data []byte) (*TieringDescriptor, error) {
+	d := &TieringDescriptor{}
+	offset := 0
+	readField := func() (string, error) {
+		if offset+2 > len(data) {
+			return "", fmt.Errorf("truncated tiering descriptor at offset %d", offset)
+		}
+		fieldLen := int(util.BytesToUint16(data[offset : offset+2]))
+		offset += 2
+		if offset+fieldLen > len(data) {
+			return "", fmt.Errorf("truncated tiering descriptor field at offset %d", offset)
+		}
+		value := string(data[offset : offset+fieldLen])
+		offset += fieldLen
+		return value, nil
+	}
+	var err error
+	if d.BackendId, err = readField(); err != nil {
+		return nil, err
+	}
+	if d.Bucket, err = readField(); err != nil {
+		return nil, err
+	}
+	if d.KeyPrefix, err = readField(); err != nil {
+		return nil, err
+	}
+	if offset+16 > len(data) {
+		return nil, fmt.Errorf("truncated tiering descriptor epoch/chunk size")
+	}
+	d.UploadEpoch = int64(util.BytesToUint64(data[offset : offset+8]))
+	d.ChunkSize = int64(util.BytesToUint64(data[offset+8 : offset+16]))
+	return d, nil
+}
+
+// TieringDescriptor returns the volume's tiering descriptor, or nil if the
+// volume's data still lives locally.
+func (s *SuperBlock) TieringDescriptor() (*TieringDescriptor, error) {
+	frame := s.FrameByType(FrameTypeTieredStorage)
+	if frame == nil {
+		return nil, nil
+	}
+	return unmarshalTieringDescriptor(frame.Data)
+}
+
+// SetTieringDescriptor marks the volume as offloaded to remote storage,
+// replacing any previous tiering descriptor.
+func (s *SuperBlock) SetTieringDescriptor(d *TieringDescriptor) {
+	newFrame := ExtensionFrame{Type: FrameTypeTieredStorage, Data: marshalTieringDescriptor(d)}
+	for i := range s.Frames {
+		if s.Frames[i].Type == FrameTypeTieredStorage {
+			s.Frames[i] = newFrame
+			return
+		}
+	}
+	s.Frames = append(s.Frames, newFrame)
+}
+
+// TieredBackend range-reads needle bytes out of whichever object store a
+// volume was offloaded to. Implementations are registered by BackendId so
+// ReadRemoteNeedle can serve a remote volume without needing to know about
+// any particular cloud SDK.
+type TieredBackend interface {
+	// ReadRange fetches [offset, offset+size) of the object at bucket/key.
+	ReadRange(bucket, key string, offset, size int64) (io.ReadCloser, error)
+}
+
+var tieredBackends = map[string]TieredBackend{}
+
+// RegisterTieredBackend makes a backend implementation available under id,
+// so a volume whose TieringDescriptor.BackendId is id can be read back.
+func RegisterTieredBackend(id string, backend TieredBackend) {
+	tieredBackends[id] = backend
+}
+
+func lookupTieredBackend(id string) (TieredBackend, error) {
+	backend, ok := tieredBackends[id]
+	if !ok {
+		return nil, fmt.Errorf("no tiered backend registered for %q", id)
+	}
+	return backend, nil
+}
+
+// remoteObjectKey returns the object key holding the given needle range,
+// derived from the descriptor's key prefix and chunk size so a large volume
+// offloaded in fixed-size chunks can be range-addressed.
+func (d *TieringDescriptor) remoteObjectKey(offset int64) string {
+	chunkIndex := offset / d.ChunkSize
+	return fmt.Sprintf("%s/chunk-%d", d.KeyPrefix, chunkIndex)
+}
+
+// IsRemote reports whether this volume's data has been offloaded to a
+// tiered backend, in which case the caller must not expect a local .dat
+// file to be open for it.
+func (v *Volume) IsRemote() bool {
+	descriptor, err := v.SuperBlock.TieringDescriptor()
+	return err == nil && descriptor != nil
+}
+
+// ReadRemoteNeedle range-GETs needle bytes for a volume that has been
+// offloaded to remote storage, going through the on-disk LRU cache first.
+func (v *Volume) ReadRemoteNeedle(offset, size int64) ([]byte, error) {
+	descriptor, err := v.SuperBlock.TieringDescriptor()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read tiering descriptor: %v", err)
+	}
+	if descriptor == nil {
+		return nil, fmt.Errorf("volume %d is not tiered to remote storage", v.Id)
+	}
+
+	cacheKey := fmt.Sprintf("%d:%d:%d", v.Id, offset, size)
+	if v.remoteCache != nil {
+		if cached, ok := v.remoteCache.Get(cacheKey); ok {
+			return cached, nil
+		}
+	}
+
+	backend, err := lookupTieredBackend(descriptor.BackendId)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readAcrossChunks(backend, descriptor, offset, size)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read remote needle for volume %d: %v", v.Id, err)
+	}
+
+	if v.remoteCache != nil {
+		v.remoteCache.Add(cacheKey, data)
+	}
+	return data, nil
+}
+
+// readAcrossChunks fetches [offset, offset+size) from the backend, stitching
+// together as many consecutive chunk objects as the range spans. A needle
+// range is not guaranteed to fit inside a single ChunkSize-sized remote
+// object, since ChunkSize is chosen for upload convenience, not to align
+// with needle boundaries.
+func readAcrossChunks(backend TieredBackend, descriptor *TieringDescriptor, offset, size int64) ([]byte, error) {
+	data := make([]byte, 0, size)
+	for int64(len(data)) < size {
+		current := offset + int64(len(data))
+		chunkOffset := current % descriptor.ChunkSize
+		chunkRemaining := descriptor.ChunkSize - chunkOffset
+		want := size - int64(len(data))
+		if want > chunkRemaining {
+			want = chunkRemaining
+		}
+
+		reader, err := backend.ReadRange(descriptor.Bucket, descriptor.remoteObjectKey(current), chunkOffset, want)
+		if err != nil {
+			return nil, err
+		}
+		chunk := make([]byte, want)
+		_, err = io.ReadFull(reader, chunk)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+	}
+	return data, nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestTieringDescriptorRoundTrip(t *testing.T) {
+	sb := &SuperBlock{}
+	sb.SetTieringDescriptor(&TieringDescriptor{
+		BackendId:   "s3",
+		Bucket:      "my-bucket",
+		KeyPrefix:   "volumes/7",
+		UploadEpoch: 1700000000,
+		ChunkSize:   1 << 20,
+	})
+
+	got, err := sb.TieringDescriptor()
+	if err != nil {
+		t.Fatalf("TieringDescriptor: %v", err)
+	}
+	if got.BackendId != "s3" || got.Bucket != "my-bucket" || got.KeyPrefix != "volumes/7" || got.ChunkSize != 1<<20 {
+		t.Fatalf("unexpected descriptor: %+v", got)
+	}
+}
+
+// fakeChunkedBackend serves range reads out of a fixed-size set of in-memory
+// chunk objects, so tests can exercise needle ranges that span more than one
+// chunk without any real object store.
+type fakeChunkedBackend struct {
+	chunks map[string][]byte
+}
+
+func (b *fakeChunkedBackend) ReadRange(bucket, key string, offset, size int64) (io.ReadCloser, error) {
+	chunk, ok := b.chunks[key]
+	if !ok {
+		return nil, fmt.Errorf("no such chunk %s", key)
+	}
+	if offset+size > int64(len(chunk)) {
+		return nil, fmt.Errorf("range [%d,%d) out of bounds for chunk of size %d", offset, offset+size, len(chunk))
+	}
+	return ioutil.NopCloser(bytes.NewReader(chunk[offset : offset+size])), nil
+}
+
+func TestReadAcrossChunksSpansChunkBoundary(t *testing.T) {
+	const chunkSize = 4
+	descriptor := &TieringDescriptor{KeyPrefix: "vol", ChunkSize: chunkSize}
+	backend := &fakeChunkedBackend{chunks: map[string][]byte{
+		descriptor.remoteObjectKey(0): []byte("ABCD"),
+		descriptor.remoteObjectKey(chunkSize): []byte("EFGH"),
+	}}
+
+	// Needle spans offsets [2, 6), crossing the chunk-0/chunk-1 boundary.
+	data, err := readAcrossChunks(backend, descriptor, 2, 4)
+	if err != nil {
+		t.Fatalf("readAcrossChunks: %v", err)
+	}
+	if string(data) != "CDEF" {
+		t.Fatalf("data = %q, want %q", data, "CDEF")
+	}
+}
+
+func TestRemoteNeedleCacheEvictsOldestEntry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "remote-needle-cache")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	cache, err := NewRemoteNeedleCache(dir, 2)
+	if err != nil {
+		t.Fatalf("NewRemoteNeedleCache: %v", err)
+	}
+
+	cache.Add("a", []byte("1"))
+	cache.Add("b", []byte("2"))
+	cache.Add("c", []byte("3")) // evicts "a"
+
+	if _, ok := cache.Get("a"); ok {
+		t.Fatalf("expected \"a\" to have been evicted")
+	}
+	if data, ok := cache.Get("c"); !ok || string(data) != "3" {
+		t.Fatalf("expected \"c\" to still be cached, got %q, ok=%v", data, ok)
+	}
+}
@@ -0,0 +1,18 @@
+package storage
+
+// Version is the on-disk super block format a volume was written with.
+type Version uint8
+
+const (
+	Version1 Version = 1
+	Version2 Version = 2
+	Version3 Version = 3
+	Version4 Version = 4
+
+	// CurrentVersion is written to every newly created volume. It is
+	// Version4 so new volumes get the checksummed, TLV-extensible super
+	// block format; existing Version2/Version3 volumes keep being read in
+	// their original format until explicitly migrated (see
+	// UpgradeSuperBlock).
+	CurrentVersion = Version4
+)